@@ -0,0 +1,141 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// changelogSection is one heading in a --group_by changelog, e.g.
+// "Features" or "Bug Fixes", holding the merged pull requests that fall
+// into it.
+type changelogSection struct {
+	Title        string
+	PullRequests []changelogItem
+}
+
+// changelogItem pairs a pull request with the repo key it was filed
+// under, so that sections can be sorted deterministically once their
+// pull requests have been collected out of Report's per-repo maps.
+type changelogItem struct {
+	RepoKey string
+	PR      *PullRequest
+}
+
+// kindLabelSections maps well-known "kind/*" labels to the changelog
+// section they belong under. Anything under "area/*" gets its own
+// "Area: <name>" section instead.
+var kindLabelSections = map[string]string{
+	"kind/bug":     "Bug Fixes",
+	"kind/feature": "Features",
+}
+
+// buildChangelog buckets every merged pull request in r into sections,
+// according to groupBy ("label", "milestone", or "type"). Callers wanting
+// to restrict the changelog to a single milestone should filter r with
+// filterReportByMilestone before calling buildChangelog.
+func buildChangelog(r *Report, groupBy string) ([]changelogSection, error) {
+	sectionsByTitle := make(map[string][]changelogItem)
+	var order []string
+
+	addTo := func(title, repoKey string, pr *PullRequest) {
+		if _, ok := sectionsByTitle[title]; !ok {
+			order = append(order, title)
+		}
+		sectionsByTitle[title] = append(sectionsByTitle[title], changelogItem{RepoKey: repoKey, PR: pr})
+	}
+
+	for repoKey, pulls := range r.ClosedPullRequests {
+		for _, pr := range pulls {
+			if pr.Merged == nil || !*pr.Merged {
+				continue
+			}
+
+			switch groupBy {
+			case "label":
+				addTo(sectionForLabels(pr.Labels, pr.Title), repoKey, pr)
+			case "milestone":
+				name := pr.Milestone
+				if name == "" {
+					name = "Unassigned"
+				}
+				addTo(name, repoKey, pr)
+			case "type":
+				addTo(sectionForTitle(pr.Title), repoKey, pr)
+			default:
+				return nil, fmt.Errorf("unknown --group_by %q: want one of label, milestone, type", groupBy)
+			}
+		}
+	}
+
+	sort.Strings(order)
+	sections := make([]changelogSection, 0, len(order))
+	for _, title := range order {
+		items := sectionsByTitle[title]
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].RepoKey != items[j].RepoKey {
+				return items[i].RepoKey < items[j].RepoKey
+			}
+			return items[i].PR.Number < items[j].PR.Number
+		})
+		sections = append(sections, changelogSection{Title: title, PullRequests: items})
+	}
+	return sections, nil
+}
+
+// sectionForLabels returns the changelog section a pull request belongs
+// under given its labels, falling back to a title-prefix heuristic when
+// none of its labels are recognized.
+func sectionForLabels(labels []string, title string) string {
+	for _, l := range labels {
+		if section, ok := kindLabelSections[l]; ok {
+			return section
+		}
+		if area, ok := strings.CutPrefix(l, "area/"); ok {
+			return "Area: " + area
+		}
+	}
+	return sectionForTitle(title)
+}
+
+// sectionForTitle maps a conventional-commit-style title prefix
+// (fix:, feat:, docs:) to a changelog section, falling back to "Other".
+func sectionForTitle(title string) string {
+	switch {
+	case strings.HasPrefix(title, "fix:"):
+		return "Bug Fixes"
+	case strings.HasPrefix(title, "feat:"):
+		return "Features"
+	case strings.HasPrefix(title, "docs:"):
+		return "Documentation"
+	default:
+		return "Other"
+	}
+}
+
+// renderChangelog writes sections as a release-notes-style markdown
+// changelog.
+func renderChangelog(w io.Writer, sections []changelogSection) {
+	for _, section := range sections {
+		fmt.Fprintf(w, "### %s\n\n", section.Title)
+		for _, item := range section.PullRequests {
+			fmt.Fprintf(w, "-   [%s](%s)\n", item.PR.Title, item.PR.URL)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -21,20 +21,34 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/go-github/v42/github"
+	"github.com/google/go-github/v60/github"
 )
 
+// stringSliceFlag implements flag.Value, allowing a flag such as
+// --gitlab_url to be repeated on the command line to supply multiple
+// values.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 // githubToken returns the github token to use. Priority is given to the
 // cmd line flag, then env var.
 func githubToken() (string, error) {
 	if *githubTokenFlag != "" {
-		fmt.Println("Using GitHub personal access token provided via flag.")
+		fmt.Fprintln(stderr, "Using GitHub personal access token provided via flag.")
 		return *githubTokenFlag, nil
 	}
 
 	githubToken := strings.TrimSpace(os.Getenv("GITHUB_TOKEN"))
 	if githubToken != "" {
-		fmt.Println("Using GitHub personal access token found in $GITHUB_TOKEN.")
+		fmt.Fprintln(stderr, "Using GitHub personal access token found in $GITHUB_TOKEN.")
 		return githubToken, nil
 
 	}
@@ -47,18 +61,18 @@ func githubToken() (string, error) {
 // attempted from the provided GitHub access token.
 func username(ctx context.Context, ghClient *github.Client) (string, error) {
 	if *userFlag != "" {
-		fmt.Printf("User identified as %s via flag\n", *userFlag)
+		fmt.Fprintf(stderr, "User identified as %s via flag\n", *userFlag)
 		return *userFlag, nil
 	}
 
 	envvarUsername := strings.TrimSpace(os.Getenv("GITHUB_USERNAME"))
 	if envvarUsername != "" {
-		fmt.Printf("User identified as %s via environment variable\n", envvarUsername)
+		fmt.Fprintf(stderr, "User identified as %s via environment variable\n", envvarUsername)
 		return envvarUsername, nil
 	}
 
 	// If a GitHub Personal Access Token was provided, we can identify the user login
-	fmt.Println(
+	fmt.Fprintln(stderr,
 		"User not specified via flag or environment variable,",
 		"attempting to detect from access token.")
 
@@ -67,7 +81,7 @@ func username(ctx context.Context, ghClient *github.Client) (string, error) {
 		return "", fmt.Errorf("failed to identify user")
 	}
 
-	fmt.Printf("User identified as %s\n", *user.Login)
+	fmt.Fprintf(stderr, "User identified as %s\n", *user.Login)
 	return *user.Login, nil
 }
 
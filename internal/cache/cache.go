@@ -0,0 +1,136 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache provides an on-disk HTTP response cache keyed by request
+// URL. lastweek's inner loop issues one extra PullRequests.Get per
+// commented-on PR, which for a heavy reviewer can mean dozens of API
+// calls per invocation; caching those responses and reusing them via
+// conditional requests keeps repeat runs well under GitHub's rate limits.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// entry is what's persisted to disk for a single cached response.
+type entry struct {
+	ETag string `json:"etag"`
+	Body []byte `json:"body"`
+}
+
+// Cache stores HTTP response bodies on disk, keyed by request URL, along
+// with the ETag each was served with.
+type Cache struct {
+	dir string
+}
+
+// New returns a Cache backed by dir, creating it if it doesn't already
+// exist.
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the cached ETag and body for url, if present.
+func (c *Cache) Get(url string) (etag string, body []byte, ok bool) {
+	data, err := os.ReadFile(c.path(url))
+	if err != nil {
+		return "", nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return "", nil, false
+	}
+	return e.ETag, e.Body, true
+}
+
+// Set stores etag and body as the cached response for url.
+func (c *Cache) Set(url, etag string, body []byte) error {
+	data, err := json.Marshal(entry{ETag: etag, Body: body})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(url), data, 0o644)
+}
+
+// Transport wraps an http.RoundTripper, adding If-None-Match to GET
+// requests for URLs already in Cache, and serving the cached body back
+// whenever the server responds 304 Not Modified.
+type Transport struct {
+	Cache *Cache
+
+	// Base is the underlying RoundTripper used to make requests.
+	// http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	url := req.URL.String()
+	cachedETag, _, cached := t.Cache.Get(url)
+	if cached && req.Method == http.MethodGet {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cachedETag)
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached {
+		_, body, _ := t.Cache.Get(url)
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = http.StatusText(http.StatusOK)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+
+	if resp.StatusCode == http.StatusOK && req.Method == http.MethodGet {
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			if err := t.Cache.Set(url, etag, body); err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	return resp, nil
+}
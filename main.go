@@ -21,14 +21,13 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/signal"
-	"sort"
-	"strings"
+	"path/filepath"
 	"time"
 
+	"github.com/crwilcox/lastweek/internal/cache"
 	"github.com/google/go-github/v60/github"
 	"golang.org/x/oauth2"
 )
@@ -43,8 +42,47 @@ var (
 	endDateFlag     = flag.String("end_date", "", "The end date in ISO layout. E.g. YYYY-MM-DD")
 	startOfWeekFlag = flag.String("start_of_week", "Saturday", "The first day of your snippet week")
 	weeksBackFlag   = flag.Int("weeks_back", 1, "The number of weeks ago to see snippets for")
+
+	gerritURLFlag  = flag.String("gerrit_url", "", "Base URL of a Gerrit instance to include in the report, e.g. https://go-review.googlesource.com")
+	gerritUserFlag = flag.String("gerrit_user", "", "Your username on the Gerrit instance at --gerrit_url, if it differs from --user/$GITHUB_USERNAME.")
+
+	gitlabURLFlag   stringSliceFlag
+	gitlabTokenFlag stringSliceFlag
+	gitlabUserFlag  stringSliceFlag
+
+	useGraphQLFlag = flag.Bool("use_graphql", false, "Query GitHub's v4 GraphQL API instead of the REST events API. "+
+		"This avoids the ~90-day/300-event ceiling of the REST API, at the cost of requiring a token with read access.")
+
+	formatFlag = flag.String("format", "md", "Output format: md, json, html, or slack.")
+
+	cacheDirFlag = flag.String("cache_dir", defaultCacheDir(), "Directory used to cache fetched issues/PRs/events between runs.")
+	noCacheFlag  = flag.Bool("no_cache", false, "Disable the on-disk response cache.")
+
+	groupByFlag   = flag.String("group_by", "", "Produce a release-notes-style changelog of merged pull requests, grouped by label, milestone, or type, instead of the default chronological report.")
+	milestoneFlag = flag.String("milestone", "", "Restrict the report to issues and pull requests assigned to this milestone.")
+
+	concurrencyFlag = flag.Int("concurrency", 8, "Number of workers used to fetch commented-on pull requests in parallel.")
 )
 
+// defaultCacheDir returns $XDG_CACHE_HOME/lastweek, falling back to the
+// OS's standard user cache directory if XDG_CACHE_HOME isn't set.
+func defaultCacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "lastweek")
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lastweek")
+}
+
+func init() {
+	flag.Var(&gitlabURLFlag, "gitlab_url", "Base URL of a GitLab instance to include in the report, e.g. https://gitlab.com. May be repeated.")
+	flag.Var(&gitlabTokenFlag, "gitlab_token", "Access token for the GitLab instance at the same position as --gitlab_url. May be repeated.")
+	flag.Var(&gitlabUserFlag, "gitlab_user", "Your username on the GitLab instance at the same position as --gitlab_url, if it differs from --user/$GITHUB_USERNAME. May be repeated.")
+}
+
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
@@ -64,10 +102,10 @@ func innerMain(ctx context.Context) error {
 	// Parse envvars and flags
 	githubToken, err := githubToken()
 	if err != nil {
-		fmt.Println(
-			"$GITHUB_TOKEN or -token flag not set - GitHub may block your " +
-				"queries due to rate-limiting " +
-				"(https://help.github.com/articles/creating-a-personal-access-token-for-the-command-line/). " +
+		fmt.Fprintln(stderr,
+			"$GITHUB_TOKEN or -token flag not set - GitHub may block your "+
+				"queries due to rate-limiting "+
+				"(https://help.github.com/articles/creating-a-personal-access-token-for-the-command-line/). "+
 				"Also note private repository activity will not be reported")
 	} else {
 		// If a GitHub Personal Access Token was provided, authenticate with it.
@@ -77,6 +115,17 @@ func innerMain(ctx context.Context) error {
 		)
 		client = oauth2.NewClient(ctx, tokenSrc)
 	}
+
+	if !*noCacheFlag && *cacheDirFlag != "" {
+		respCache, err := cache.New(*cacheDirFlag)
+		if err != nil {
+			return fmt.Errorf("failed to open response cache: %v", err)
+		}
+		client = &http.Client{
+			Transport: &cache.Transport{Cache: respCache, Base: client.Transport},
+		}
+	}
+
 	ghClient := github.NewClient(client)
 
 	githubUsername, err := username(ctx, ghClient)
@@ -91,288 +140,52 @@ func innerMain(ctx context.Context) error {
 	fmt.Fprintf(stderr, "Pulling contributions from %s to %s...\n",
 		startTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
 
-	openedIssues := make(map[string]map[int]*github.Issue)
-	closedIssues := make(map[string]map[int]*github.Issue)
-	commentedIssues := make(map[string]map[int]*github.Issue)
-	openedPullRequests := make(map[string]map[int]*github.PullRequest)
-	reviewedPullRequests := make(map[string]map[int]*github.PullRequest)
-	closedPullRequests := make(map[string]map[int]*github.PullRequest)
-
-	options := &github.ListOptions{Page: 0}
-	for {
-		events, resp, err := ghClient.Activity.ListEventsPerformedByUser(ctx, githubUsername, false, options)
-		if err != nil {
-			return err
-		}
-
-		// Process each event within the page
-		for _, event := range events {
-			if event.CreatedAt.Before(startTime) || event.CreatedAt.After(endTime) {
-				continue
-			}
-
-			repo := event.Repo
-			payload, err := event.ParsePayload()
-			if err != nil {
-				return fmt.Errorf("failed to parse event payload: %v", err)
-			}
-			switch p := payload.(type) {
-			case *github.IssueCommentEvent:
-				issue := p.Issue
-				if issue == nil {
-					return fmt.Errorf("issue is nil: %v", p)
-				}
-				switch *p.Action {
-				case "created":
-					if p.Issue.IsPullRequest() {
-						// Pull requests are issues, if a comment is left on a
-						// PR that wasn't opened by the user, consider that
-						// part of PR review.
-						if *p.Issue.User.Login != githubUsername {
-							if reviewedPullRequests[*repo.Name] == nil {
-								reviewedPullRequests[*repo.Name] = make(map[int]*github.PullRequest)
-							}
-							s := strings.Split(*repo.Name, "/")
-							pull, _, err := ghClient.PullRequests.Get(ctx, s[0], s[1], *issue.Number)
-							if err != nil {
-								return err
-							}
-							reviewedPullRequests[*repo.Name][*issue.Number] = pull
-						}
-					} else {
-						if commentedIssues[*repo.Name] == nil {
-							commentedIssues[*repo.Name] = make(map[int]*github.Issue)
-						}
-						commentedIssues[*repo.Name][*issue.Number] = issue
-					}
-				}
-			case *github.IssuesEvent:
-				issue := p.Issue
-				if issue == nil {
-					return fmt.Errorf("issue is nil: %v", p)
-				}
-
-				switch *p.Action {
-				case "opened":
-					if openedIssues[*repo.Name] == nil {
-						openedIssues[*repo.Name] = make(map[int]*github.Issue)
-					}
-					openedIssues[*repo.Name][*issue.Number] = p.Issue
-				case "closed":
-					if closedIssues[*repo.Name] == nil {
-						closedIssues[*repo.Name] = make(map[int]*github.Issue)
-					}
-					closedIssues[*repo.Name][*issue.Number] = p.Issue
-				}
-			case *github.PullRequestEvent:
-				pullRequest := p.PullRequest
-				if pullRequest == nil {
-					return fmt.Errorf("pullRequest is nil: %v", p)
-				}
-
-				switch *p.Action {
-				case "created", "opened", "reopened":
-					if openedPullRequests[*repo.Name] == nil {
-						openedPullRequests[*repo.Name] = make(map[int]*github.PullRequest)
-					}
-					openedPullRequests[*repo.Name][*pullRequest.Number] = p.PullRequest
-				case "closed": // Heh.
-					if closedPullRequests[*repo.Name] == nil {
-						closedPullRequests[*repo.Name] = make(map[int]*github.PullRequest)
-					}
-					closedPullRequests[*repo.Name][*pullRequest.Number] = p.PullRequest
-				}
-			case *github.PullRequestReviewCommentEvent:
-				pullRequest := p.PullRequest
-				if pullRequest == nil {
-					return fmt.Errorf("pullRequest is nil: %v", p)
-				}
-
-				switch *p.Action {
-				case "created":
-					if reviewedPullRequests[*repo.Name] == nil {
-						reviewedPullRequests[*repo.Name] = make(map[int]*github.PullRequest)
-					}
-					reviewedPullRequests[*repo.Name][*pullRequest.Number] = p.PullRequest
-				}
-			default:
-				// Ignore.
-				continue
-			}
-
-		}
-
-		// Pages will loop around, if the next page is less, we have already seen it.
-		if options.Page == resp.LastPage || resp.NextPage < options.Page {
-			break
-		}
-		options.Page = resp.NextPage
-	}
-
-	var w strings.Builder
-
-	if len(openedIssues) > 0 {
-		repos := make([]string, 0, len(openedIssues))
-		for k := range openedIssues {
-			repos = append(repos, k)
-		}
-
-		fmt.Fprintf(&w, "### Opened issues\n\n")
-		for _, r := range repos {
-			formatRepo(&w, r)
-
-			issues := sortIssues(openedIssues[r])
-			for _, i := range issues {
-				formatIssue(&w, i)
-			}
-			fmt.Fprintln(&w)
-		}
-		fmt.Fprintln(&w)
-	}
-
-	if len(closedIssues) > 0 {
-		repos := make([]string, 0, len(closedIssues))
-		for k := range closedIssues {
-			repos = append(repos, k)
-		}
-
-		fmt.Fprintf(&w, "### Closed issues\n\n")
-		for _, r := range repos {
-			formatRepo(&w, r)
-
-			issues := sortIssues(closedIssues[r])
-			for _, i := range issues {
-				formatIssue(&w, i)
-			}
-			fmt.Fprintln(&w)
-		}
-		fmt.Fprintln(&w)
-	}
-
-	if len(commentedIssues) > 0 {
-		repos := make([]string, 0, len(commentedIssues))
-		for k := range commentedIssues {
-			repos = append(repos, k)
-		}
-
-		fmt.Fprintf(&w, "### Commented issues\n\n")
-		for _, r := range repos {
-			formatRepo(&w, r)
-
-			issues := sortIssues(commentedIssues[r])
-			for _, i := range issues {
-				formatIssue(&w, i)
-			}
-			fmt.Fprintln(&w)
-		}
-		fmt.Fprintln(&w)
+	var githubSource Source
+	if *useGraphQLFlag {
+		githubSource = &GitHubGraphQLSource{Client: client, Token: githubToken}
+	} else {
+		githubSource = &GitHubSource{Client: ghClient, Concurrency: *concurrencyFlag}
 	}
+	sources := []Source{githubSource}
 
-	if len(openedPullRequests) > 0 {
-		repos := make([]string, 0, len(openedPullRequests))
-		for k := range openedPullRequests {
-			repos = append(repos, k)
-		}
-
-		fmt.Fprintf(&w, "### Pull requests opened\n\n")
-		for _, r := range repos {
-			formatRepo(&w, r)
-
-			pullRequests := sortPullRequests(openedPullRequests[r])
-			for _, i := range pullRequests {
-				formatPullRequest(&w, i)
-			}
-			fmt.Fprintln(&w)
-		}
-		fmt.Fprintln(&w)
+	if *gerritURLFlag != "" {
+		sources = append(sources, &GerritSource{BaseURL: *gerritURLFlag, Username: *gerritUserFlag})
 	}
 
-	if len(closedPullRequests) > 0 {
-		repos := make([]string, 0, len(closedPullRequests))
-		for k := range closedPullRequests {
-			repos = append(repos, k)
+	for i, gitlabURL := range gitlabURLFlag {
+		var token, username string
+		if i < len(gitlabTokenFlag) {
+			token = gitlabTokenFlag[i]
 		}
-
-		fmt.Fprintf(&w, "### Pull requests closed\n\n")
-		for _, r := range repos {
-			formatRepo(&w, r)
-
-			pullRequests := sortPullRequests(closedPullRequests[r])
-			for _, i := range pullRequests {
-				formatPullRequest(&w, i)
-			}
-			fmt.Fprintln(&w)
+		if i < len(gitlabUserFlag) {
+			username = gitlabUserFlag[i]
 		}
-		fmt.Fprintln(&w)
+		sources = append(sources, &GitLabSource{BaseURL: gitlabURL, Token: token, Username: username})
 	}
 
-	if len(reviewedPullRequests) > 0 {
-		repos := make([]string, 0, len(reviewedPullRequests))
-		for k := range reviewedPullRequests {
-			repos = append(repos, k)
-		}
-
-		fmt.Fprintf(&w, "### Code reviews\n\n")
-		for _, r := range repos {
-			formatRepo(&w, r)
-
-			pullRequests := sortPullRequests(reviewedPullRequests[r])
-			for _, i := range pullRequests {
-				formatPullRequest(&w, i)
-			}
-			fmt.Fprintln(&w)
+	reports := make([]*Report, 0, len(sources))
+	for _, source := range sources {
+		report, err := source.FetchActivity(ctx, githubUsername, startTime, endTime)
+		if err != nil {
+			return err
 		}
-		fmt.Fprintln(&w)
+		reports = append(reports, report)
 	}
+	report := mergeReports(reports...)
+	report = filterReportByMilestone(report, *milestoneFlag)
 
-	fmt.Println(w.String())
-
-	return nil
-}
-
-func formatRepo(w io.Writer, s string) {
-	fmt.Fprintf(w, "-   **%s**\n\n", s)
-}
-
-func formatIssue(w io.Writer, i *github.Issue) {
-	fmt.Fprintf(w, "    -   [%s](%s)\n", *i.Title, *i.HTMLURL)
-}
-
-func formatMerged(w io.Writer, i *github.PullRequest) {
-	if i.Merged != nil {
-		if *i.Merged {
-			fmt.Fprintf(w, " [merged]\n")
-		} else {
-			fmt.Fprintf(w, " [not merged]\n")
+	if *groupByFlag != "" {
+		sections, err := buildChangelog(report, *groupByFlag)
+		if err != nil {
+			return err
 		}
-	} else {
-		fmt.Fprintf(w, "\n")
-	}
-}
-
-func formatPullRequest(w io.Writer, i *github.PullRequest) {
-	fmt.Fprintf(w, "    -   [%s](%s)", *i.Title, *i.HTMLURL)
-	formatMerged(w, i)
-}
-
-func sortIssues(m map[int]*github.Issue) []*github.Issue {
-	issues := make([]*github.Issue, 0, len(m))
-	for _, i := range m {
-		issues = append(issues, i)
+		renderChangelog(stdout, sections)
+		return nil
 	}
-	sort.Slice(issues, func(i, j int) bool {
-		return *issues[i].Number < *issues[j].Number
-	})
-	return issues
-}
 
-func sortPullRequests(m map[int]*github.PullRequest) []*github.PullRequest {
-	pullRequests := make([]*github.PullRequest, 0, len(m))
-	for _, i := range m {
-		pullRequests = append(pullRequests, i)
+	renderer, err := rendererForFormat(*formatFlag)
+	if err != nil {
+		return err
 	}
-	sort.Slice(pullRequests, func(i, j int) bool {
-		return *pullRequests[i].Number < *pullRequests[j].Number
-	})
-	return pullRequests
+	return renderer.RenderReport(stdout, report)
 }
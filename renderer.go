@@ -0,0 +1,88 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Renderer writes a Report to w in some output format. Implementations
+// must not mutate r.
+type Renderer interface {
+	RenderReport(w io.Writer, r *Report) error
+}
+
+// rendererForFormat returns the Renderer registered for the given
+// --format value.
+func rendererForFormat(format string) (Renderer, error) {
+	switch format {
+	case "", "md", "markdown":
+		return &MarkdownRenderer{}, nil
+	case "json":
+		return &JSONRenderer{}, nil
+	case "html":
+		return &HTMLRenderer{}, nil
+	case "slack":
+		return &SlackRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown --format %q: want one of md, json, html, slack", format)
+	}
+}
+
+// sortedRepoKeys returns the "host/owner/repo" keys of m in sorted order,
+// so that rendered output is deterministic across runs.
+func sortedRepoKeys[V any](m map[string]map[int]V) []string {
+	repos := make([]string, 0, len(m))
+	for k := range m {
+		repos = append(repos, k)
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// sortedCommitKeys returns the "host/owner/repo" keys of m in sorted
+// order, so that rendered output is deterministic across runs.
+func sortedCommitKeys(m map[string]int) []string {
+	repos := make([]string, 0, len(m))
+	for k := range m {
+		repos = append(repos, k)
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+func sortIssues(m map[int]*Issue) []*Issue {
+	issues := make([]*Issue, 0, len(m))
+	for _, i := range m {
+		issues = append(issues, i)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Number < issues[j].Number
+	})
+	return issues
+}
+
+func sortPullRequests(m map[int]*PullRequest) []*PullRequest {
+	pullRequests := make([]*PullRequest, 0, len(m))
+	for _, i := range m {
+		pullRequests = append(pullRequests, i)
+	}
+	sort.Slice(pullRequests, func(i, j int) bool {
+		return pullRequests[i].Number < pullRequests[j].Number
+	})
+	return pullRequests
+}
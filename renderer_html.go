@@ -0,0 +1,95 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+// HTMLRenderer renders a Report as a single self-contained HTML page, with
+// one <section id="..."> per activity type so the report can be linked to
+// directly, e.g. for embedding in an internal dashboard.
+type HTMLRenderer struct{}
+
+// RenderReport implements Renderer.
+func (HTMLRenderer) RenderReport(w io.Writer, r *Report) error {
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, `<html><head><meta charset="utf-8"><title>lastweek report</title></head><body>`)
+
+	renderHTMLIssueSection(w, "opened-issues", "Opened issues", r.OpenedIssues)
+	renderHTMLIssueSection(w, "closed-issues", "Closed issues", r.ClosedIssues)
+	renderHTMLIssueSection(w, "commented-issues", "Commented issues", r.CommentedIssues)
+	renderHTMLPullRequestSection(w, "opened-pull-requests", "Pull requests opened", r.OpenedPullRequests)
+	renderHTMLPullRequestSection(w, "closed-pull-requests", "Pull requests closed", r.ClosedPullRequests)
+	renderHTMLPullRequestSection(w, "code-reviews", "Code reviews", r.ReviewedPullRequests)
+
+	if len(r.Commits) > 0 {
+		fmt.Fprintln(w, `<section id="commits">`)
+		fmt.Fprintln(w, "<h3>Commits</h3><ul>")
+		for _, repo := range sortedCommitKeys(r.Commits) {
+			fmt.Fprintf(w, "<li><strong>%s</strong>: %d commit(s)</li>\n", html.EscapeString(repo), r.Commits[repo])
+		}
+		fmt.Fprintln(w, "</ul></section>")
+	}
+
+	fmt.Fprintln(w, "</body></html>")
+	return nil
+}
+
+func renderHTMLIssueSection(w io.Writer, id, heading string, m map[string]map[int]*Issue) {
+	if len(m) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "<section id=%q>\n", id)
+	fmt.Fprintf(w, "<h3>%s</h3>\n", html.EscapeString(heading))
+	for _, repo := range sortedRepoKeys(m) {
+		fmt.Fprintf(w, "<p><strong>%s</strong></p>\n<ul>\n", html.EscapeString(repo))
+		for _, i := range sortIssues(m[repo]) {
+			fmt.Fprintf(w, `<li><a href=%q>%s</a></li>`+"\n", i.URL, html.EscapeString(i.Title))
+		}
+		fmt.Fprintln(w, "</ul>")
+	}
+	fmt.Fprintln(w, "</section>")
+}
+
+func renderHTMLPullRequestSection(w io.Writer, id, heading string, m map[string]map[int]*PullRequest) {
+	if len(m) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "<section id=%q>\n", id)
+	fmt.Fprintf(w, "<h3>%s</h3>\n", html.EscapeString(heading))
+	for _, repo := range sortedRepoKeys(m) {
+		fmt.Fprintf(w, "<p><strong>%s</strong></p>\n<ul>\n", html.EscapeString(repo))
+		for _, i := range sortPullRequests(m[repo]) {
+			fmt.Fprintf(w, `<li><a href=%q>%s</a>%s</li>`+"\n", i.URL, html.EscapeString(i.Title), mergedSuffix(i))
+		}
+		fmt.Fprintln(w, "</ul>")
+	}
+	fmt.Fprintln(w, "</section>")
+}
+
+func mergedSuffix(i *PullRequest) string {
+	if i.Merged == nil {
+		return ""
+	}
+	if *i.Merged {
+		return " [merged]"
+	}
+	return " [not merged]"
+}
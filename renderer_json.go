@@ -0,0 +1,71 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONRenderer renders a Report as a single structured JSON document,
+// suitable for scripting or piping into another tool.
+type JSONRenderer struct{}
+
+// jsonReport mirrors Report but flattens each "repo -> number -> item" map
+// into a "repo -> []item" list, since the item numbers are already present
+// on the items themselves and arrays are easier for downstream consumers
+// to work with than nested maps.
+type jsonReport struct {
+	OpenedIssues         map[string][]*Issue       `json:"openedIssues,omitempty"`
+	ClosedIssues         map[string][]*Issue       `json:"closedIssues,omitempty"`
+	CommentedIssues      map[string][]*Issue       `json:"commentedIssues,omitempty"`
+	OpenedPullRequests   map[string][]*PullRequest `json:"openedPullRequests,omitempty"`
+	ReviewedPullRequests map[string][]*PullRequest `json:"reviewedPullRequests,omitempty"`
+	ClosedPullRequests   map[string][]*PullRequest `json:"closedPullRequests,omitempty"`
+	Commits              map[string]int            `json:"commits,omitempty"`
+}
+
+// RenderReport implements Renderer.
+func (JSONRenderer) RenderReport(w io.Writer, r *Report) error {
+	out := jsonReport{
+		OpenedIssues:         issueLists(r.OpenedIssues),
+		ClosedIssues:         issueLists(r.ClosedIssues),
+		CommentedIssues:      issueLists(r.CommentedIssues),
+		OpenedPullRequests:   pullRequestLists(r.OpenedPullRequests),
+		ReviewedPullRequests: pullRequestLists(r.ReviewedPullRequests),
+		ClosedPullRequests:   pullRequestLists(r.ClosedPullRequests),
+		Commits:              r.Commits,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func issueLists(m map[string]map[int]*Issue) map[string][]*Issue {
+	out := make(map[string][]*Issue, len(m))
+	for repo := range m {
+		out[repo] = sortIssues(m[repo])
+	}
+	return out
+}
+
+func pullRequestLists(m map[string]map[int]*PullRequest) map[string][]*PullRequest {
+	out := make(map[string][]*PullRequest, len(m))
+	for repo := range m {
+		out[repo] = sortPullRequests(m[repo])
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// MarkdownRenderer renders a Report as the snippet-style markdown lastweek
+// has always produced: a "### Section" heading per activity type, with a
+// bulleted repo list and nested issue/PR links underneath.
+type MarkdownRenderer struct{}
+
+// RenderReport implements Renderer.
+func (MarkdownRenderer) RenderReport(w io.Writer, r *Report) error {
+	renderIssueSection(w, "Opened issues", r.OpenedIssues)
+	renderIssueSection(w, "Closed issues", r.ClosedIssues)
+	renderIssueSection(w, "Commented issues", r.CommentedIssues)
+	renderPullRequestSection(w, "Pull requests opened", r.OpenedPullRequests)
+	renderPullRequestSection(w, "Pull requests closed", r.ClosedPullRequests)
+	renderPullRequestSection(w, "Code reviews", r.ReviewedPullRequests)
+
+	if len(r.Commits) > 0 {
+		fmt.Fprintf(w, "### Commits\n\n")
+		for _, repo := range sortedCommitKeys(r.Commits) {
+			fmt.Fprintf(w, "-   **%s**: %d commit(s)\n", repo, r.Commits[repo])
+		}
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+func renderIssueSection(w io.Writer, heading string, m map[string]map[int]*Issue) {
+	if len(m) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "### %s\n\n", heading)
+	for _, repo := range sortedRepoKeys(m) {
+		formatRepo(w, repo)
+		for _, i := range sortIssues(m[repo]) {
+			formatIssue(w, i)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+}
+
+func renderPullRequestSection(w io.Writer, heading string, m map[string]map[int]*PullRequest) {
+	if len(m) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "### %s\n\n", heading)
+	for _, repo := range sortedRepoKeys(m) {
+		formatRepo(w, repo)
+		for _, i := range sortPullRequests(m[repo]) {
+			formatPullRequest(w, i)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+}
+
+func formatRepo(w io.Writer, s string) {
+	fmt.Fprintf(w, "-   **%s**\n\n", s)
+}
+
+func formatIssue(w io.Writer, i *Issue) {
+	fmt.Fprintf(w, "    -   [%s](%s)\n", i.Title, i.URL)
+}
+
+func formatMerged(w io.Writer, i *PullRequest) {
+	if i.Merged != nil {
+		if *i.Merged {
+			fmt.Fprintf(w, " [merged]\n")
+		} else {
+			fmt.Fprintf(w, " [not merged]\n")
+		}
+	} else {
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+func formatPullRequest(w io.Writer, i *PullRequest) {
+	fmt.Fprintf(w, "    -   [%s](%s)", i.Title, i.URL)
+	formatMerged(w, i)
+}
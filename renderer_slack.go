@@ -0,0 +1,112 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SlackRenderer renders a Report as a Slack Block Kit payload: a JSON
+// object whose "blocks" field is suitable for a chat.postMessage call.
+type SlackRenderer struct{}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackPayload struct {
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// RenderReport implements Renderer.
+func (SlackRenderer) RenderReport(w io.Writer, r *Report) error {
+	var blocks []slackBlock
+
+	blocks = append(blocks,
+		slackIssueSection("Opened issues", r.OpenedIssues)...)
+	blocks = append(blocks,
+		slackIssueSection("Closed issues", r.ClosedIssues)...)
+	blocks = append(blocks,
+		slackIssueSection("Commented issues", r.CommentedIssues)...)
+	blocks = append(blocks,
+		slackPullRequestSection("Pull requests opened", r.OpenedPullRequests)...)
+	blocks = append(blocks,
+		slackPullRequestSection("Pull requests closed", r.ClosedPullRequests)...)
+	blocks = append(blocks,
+		slackPullRequestSection("Code reviews", r.ReviewedPullRequests)...)
+
+	if len(r.Commits) > 0 {
+		var body strings.Builder
+		for _, repo := range sortedCommitKeys(r.Commits) {
+			fmt.Fprintf(&body, "*%s*: %d commit(s)\n", repo, r.Commits[repo])
+		}
+		blocks = append(blocks, slackHeader("Commits"), slackSection(body.String()))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(slackPayload{Blocks: blocks})
+}
+
+func slackIssueSection(heading string, m map[string]map[int]*Issue) []slackBlock {
+	if len(m) == 0 {
+		return nil
+	}
+
+	blocks := []slackBlock{slackHeader(heading)}
+	for _, repo := range sortedRepoKeys(m) {
+		var body strings.Builder
+		fmt.Fprintf(&body, "*%s*\n", repo)
+		for _, i := range sortIssues(m[repo]) {
+			fmt.Fprintf(&body, "\xe2\x80\xa2 <%s|%s>\n", i.URL, i.Title)
+		}
+		blocks = append(blocks, slackSection(body.String()))
+	}
+	return blocks
+}
+
+func slackPullRequestSection(heading string, m map[string]map[int]*PullRequest) []slackBlock {
+	if len(m) == 0 {
+		return nil
+	}
+
+	blocks := []slackBlock{slackHeader(heading)}
+	for _, repo := range sortedRepoKeys(m) {
+		var body strings.Builder
+		fmt.Fprintf(&body, "*%s*\n", repo)
+		for _, i := range sortPullRequests(m[repo]) {
+			fmt.Fprintf(&body, "\xe2\x80\xa2 <%s|%s>%s\n", i.URL, i.Title, mergedSuffix(i))
+		}
+		blocks = append(blocks, slackSection(body.String()))
+	}
+	return blocks
+}
+
+func slackHeader(text string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: text}}
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Issue is a forge-agnostic representation of an issue (or the nearest
+// equivalent on a given forge) suitable for rendering in a report.
+type Issue struct {
+	Number    int
+	Title     string
+	URL       string
+	Labels    []string
+	Milestone string
+}
+
+// PullRequest is a forge-agnostic representation of a pull request, merge
+// request, or Gerrit change.
+type PullRequest struct {
+	Number    int
+	Title     string
+	URL       string
+	Merged    *bool
+	Labels    []string
+	Milestone string
+}
+
+// Report captures a user's contribution activity for a single reporting
+// period. Every map is keyed by a "host/owner/repo" identifier (e.g.
+// "github.com/golang/go" or "go-review.googlesource.com/go") so that
+// activity pulled from multiple Sources can be merged without collisions.
+type Report struct {
+	OpenedIssues         map[string]map[int]*Issue
+	ClosedIssues         map[string]map[int]*Issue
+	CommentedIssues      map[string]map[int]*Issue
+	OpenedPullRequests   map[string]map[int]*PullRequest
+	ReviewedPullRequests map[string]map[int]*PullRequest
+	ClosedPullRequests   map[string]map[int]*PullRequest
+
+	// Commits counts commits contributed to a repository's default
+	// branch, keyed the same way as the other fields. It is populated by
+	// sources that can see pushes REST events don't cover, e.g.
+	// GitHubGraphQLSource via commitContributionsByRepository.
+	Commits map[string]int
+}
+
+// newReport returns an empty, fully-initialized Report.
+func newReport() *Report {
+	return &Report{
+		OpenedIssues:         make(map[string]map[int]*Issue),
+		ClosedIssues:         make(map[string]map[int]*Issue),
+		CommentedIssues:      make(map[string]map[int]*Issue),
+		OpenedPullRequests:   make(map[string]map[int]*PullRequest),
+		ReviewedPullRequests: make(map[string]map[int]*PullRequest),
+		ClosedPullRequests:   make(map[string]map[int]*PullRequest),
+		Commits:              make(map[string]int),
+	}
+}
+
+// mergeReports combines the per-source reports produced by each configured
+// Source into a single Report.
+func mergeReports(reports ...*Report) *Report {
+	merged := newReport()
+	for _, r := range reports {
+		if r == nil {
+			continue
+		}
+		mergeIssues(merged.OpenedIssues, r.OpenedIssues)
+		mergeIssues(merged.ClosedIssues, r.ClosedIssues)
+		mergeIssues(merged.CommentedIssues, r.CommentedIssues)
+		mergePullRequests(merged.OpenedPullRequests, r.OpenedPullRequests)
+		mergePullRequests(merged.ReviewedPullRequests, r.ReviewedPullRequests)
+		mergePullRequests(merged.ClosedPullRequests, r.ClosedPullRequests)
+		for repo, count := range r.Commits {
+			merged.Commits[repo] += count
+		}
+	}
+	return merged
+}
+
+func mergeIssues(dst, src map[string]map[int]*Issue) {
+	for repo, issues := range src {
+		for n, i := range issues {
+			addIssue(dst, repo, n, i)
+		}
+	}
+}
+
+func mergePullRequests(dst, src map[string]map[int]*PullRequest) {
+	for repo, pulls := range src {
+		for n, p := range pulls {
+			addPullRequest(dst, repo, n, p)
+		}
+	}
+}
+
+func addIssue(m map[string]map[int]*Issue, repo string, number int, i *Issue) {
+	if m[repo] == nil {
+		m[repo] = make(map[int]*Issue)
+	}
+	m[repo][number] = i
+}
+
+func addPullRequest(m map[string]map[int]*PullRequest, repo string, number int, p *PullRequest) {
+	if m[repo] == nil {
+		m[repo] = make(map[int]*PullRequest)
+	}
+	m[repo][number] = p
+}
+
+// filterReportByMilestone returns a copy of r containing only the issues
+// and pull requests assigned to milestone. If milestone is empty, r is
+// returned unchanged.
+func filterReportByMilestone(r *Report, milestone string) *Report {
+	if milestone == "" {
+		return r
+	}
+
+	filtered := newReport()
+	filtered.OpenedIssues = filterIssuesByMilestone(r.OpenedIssues, milestone)
+	filtered.ClosedIssues = filterIssuesByMilestone(r.ClosedIssues, milestone)
+	filtered.CommentedIssues = filterIssuesByMilestone(r.CommentedIssues, milestone)
+	filtered.OpenedPullRequests = filterPullRequestsByMilestone(r.OpenedPullRequests, milestone)
+	filtered.ReviewedPullRequests = filterPullRequestsByMilestone(r.ReviewedPullRequests, milestone)
+	filtered.ClosedPullRequests = filterPullRequestsByMilestone(r.ClosedPullRequests, milestone)
+	filtered.Commits = r.Commits
+	return filtered
+}
+
+func filterIssuesByMilestone(m map[string]map[int]*Issue, milestone string) map[string]map[int]*Issue {
+	filtered := make(map[string]map[int]*Issue)
+	for repo, issues := range m {
+		for n, i := range issues {
+			if i.Milestone == milestone {
+				addIssue(filtered, repo, n, i)
+			}
+		}
+	}
+	return filtered
+}
+
+func filterPullRequestsByMilestone(m map[string]map[int]*PullRequest, milestone string) map[string]map[int]*PullRequest {
+	filtered := make(map[string]map[int]*PullRequest)
+	for repo, pulls := range m {
+		for n, p := range pulls {
+			if p.Milestone == milestone {
+				addPullRequest(filtered, repo, n, p)
+			}
+		}
+	}
+	return filtered
+}
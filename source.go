@@ -0,0 +1,41 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Source fetches a user's contribution activity from a single forge
+// (GitHub, GitLab, Gerrit, ...) for the given time range. Reports produced
+// by different Sources are merged together by mergeReports.
+type Source interface {
+	// FetchActivity returns a Report describing user's activity between
+	// start and end.
+	FetchActivity(ctx context.Context, user string, start, end time.Time) (*Report, error)
+}
+
+// hostFromURL returns the host component of a base URL, e.g.
+// "https://gitlab.com/" becomes "gitlab.com". It is used to build the
+// "host/owner/repo" keys that Reports are merged on.
+func hostFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// gerritXSSIPrefix is prepended by Gerrit to every JSON response as a
+// defence against cross-site script inclusion attacks. It must be
+// stripped before the body can be unmarshalled.
+var gerritXSSIPrefix = []byte(")]}'\n")
+
+// gerritChange is the subset of Gerrit's ChangeInfo entity that lastweek
+// cares about. MoreChanges is only populated on the last element of a
+// page, per Gerrit's pagination contract.
+type gerritChange struct {
+	Project     string `json:"project"`
+	Number      int    `json:"_number"`
+	Subject     string `json:"subject"`
+	Status      string `json:"status"`
+	MoreChanges bool   `json:"_more_changes"`
+}
+
+// GerritSource fetches activity from a Gerrit instance's REST API.
+type GerritSource struct {
+	// BaseURL is the root of the Gerrit instance, e.g.
+	// "https://go-review.googlesource.com".
+	BaseURL string
+	Client  *http.Client
+
+	// Username, if set, is looked up on this instance instead of the
+	// user passed to FetchActivity. lastweek only takes one --user
+	// value (your GitHub login); set this when your Gerrit username
+	// differs from it.
+	Username string
+}
+
+// FetchActivity implements Source.
+func (s *GerritSource) FetchActivity(ctx context.Context, user string, start, end time.Time) (*Report, error) {
+	report := newReport()
+
+	if s.Username != "" {
+		user = s.Username
+	}
+
+	query := fmt.Sprintf("owner:%s after:%s before:%s",
+		user, start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	host := hostFromURL(s.BaseURL)
+	skip := 0
+	for {
+		reqURL := fmt.Sprintf("%s/changes/?q=%s&S=%d",
+			strings.TrimRight(s.BaseURL, "/"), url.QueryEscape(query), skip)
+
+		var changes []gerritChange
+		if err := s.get(ctx, reqURL, &changes); err != nil {
+			return nil, err
+		}
+
+		for _, c := range changes {
+			repoKey := host + "/" + c.Project
+			change := &PullRequest{
+				Number: c.Number,
+				Title:  c.Subject,
+				URL:    fmt.Sprintf("%s/c/%s/+/%d", strings.TrimRight(s.BaseURL, "/"), c.Project, c.Number),
+			}
+
+			switch c.Status {
+			case "NEW":
+				addPullRequest(report.OpenedPullRequests, repoKey, c.Number, change)
+			case "MERGED", "ABANDONED":
+				merged := c.Status == "MERGED"
+				change.Merged = &merged
+				addPullRequest(report.ClosedPullRequests, repoKey, c.Number, change)
+			}
+		}
+
+		if len(changes) == 0 || !changes[len(changes)-1].MoreChanges {
+			break
+		}
+		skip += len(changes)
+	}
+
+	return report, nil
+}
+
+func (s *GerritSource) get(ctx context.Context, reqURL string, v interface{}) error {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gerrit: unexpected status %s for %s", resp.Status, reqURL)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	body = bytes.TrimPrefix(body, gerritXSSIPrefix)
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse gerrit response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// defaultGitHubConcurrency is used when GitHubSource.Concurrency is unset.
+const defaultGitHubConcurrency = 8
+
+// maxAbuseRetries bounds how many times fetchPullRequest will back off and
+// retry a single lookup after a secondary rate limit, so a pathologically
+// persistent abuse detection response can't hang a worker forever.
+const maxAbuseRetries = 5
+
+// minRateLimitRemaining is the primary rate limit headroom below which
+// fetchPullRequest pauses until the limit resets, rather than racing the
+// rest of the worker pool toward a hard 403.
+const minRateLimitRemaining = 10
+
+// GitHubSource fetches activity from github.com (or a GitHub Enterprise
+// instance configured on the given client) using the events REST API.
+type GitHubSource struct {
+	Client *github.Client
+
+	// Concurrency is the number of workers used to look up commented-on
+	// pull requests in parallel. defaultGitHubConcurrency is used if
+	// this is zero.
+	Concurrency int
+}
+
+// pullRequestLookup is a unit of work handed from the event-paginating
+// goroutine to the pull request worker pool: "go fetch owner/repo#number
+// and file it under repoKey".
+type pullRequestLookup struct {
+	owner, repo string
+	number      int
+	repoKey     string
+}
+
+// FetchActivity implements Source. A single goroutine paginates events
+// while a pool of s.Concurrency workers perform the PullRequests.Get
+// lookup required for each commented-on pull request, since that lookup
+// is the slow part of the loop: a heavy reviewer can generate dozens of
+// them in a single run.
+func (s *GitHubSource) FetchActivity(ctx context.Context, user string, start, end time.Time) (*Report, error) {
+	report := newReport()
+	var mu sync.Mutex
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultGitHubConcurrency
+	}
+
+	lookups := make(chan pullRequestLookup)
+	errs := make(chan error, concurrency)
+
+	var workers sync.WaitGroup
+	workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer workers.Done()
+			for lookup := range lookups {
+				pull, err := s.fetchPullRequest(ctx, lookup)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+
+				mu.Lock()
+				addPullRequest(report.ReviewedPullRequests, lookup.repoKey, lookup.number, githubPullRequest(pull))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	options := &github.ListOptions{Page: 0}
+	paginationErr := func() error {
+		for {
+			events, resp, err := s.Client.Activity.ListEventsPerformedByUser(ctx, user, false, options)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stderr, "Fetched event page %d (rate limit remaining: %d/%d)\n",
+				options.Page, resp.Rate.Remaining, resp.Rate.Limit)
+
+			// Process each event within the page
+			for _, event := range events {
+				if event.CreatedAt.Before(start) || event.CreatedAt.After(end) {
+					continue
+				}
+
+				repoKey := "github.com/" + *event.Repo.Name
+				payload, err := event.ParsePayload()
+				if err != nil {
+					return fmt.Errorf("failed to parse event payload: %v", err)
+				}
+				switch p := payload.(type) {
+				case *github.IssueCommentEvent:
+					issue := p.Issue
+					if issue == nil {
+						return fmt.Errorf("issue is nil: %v", p)
+					}
+					switch *p.Action {
+					case "created":
+						if p.Issue.IsPullRequest() {
+							// Pull requests are issues, if a comment is left on a
+							// PR that wasn't opened by the user, consider that
+							// part of PR review.
+							if *p.Issue.User.Login != user {
+								owner, name := splitOwnerRepo(*event.Repo.Name)
+								lookups <- pullRequestLookup{owner: owner, repo: name, number: *issue.Number, repoKey: repoKey}
+							}
+						} else {
+							mu.Lock()
+							addIssue(report.CommentedIssues, repoKey, *issue.Number, githubIssue(issue))
+							mu.Unlock()
+						}
+					}
+				case *github.IssuesEvent:
+					issue := p.Issue
+					if issue == nil {
+						return fmt.Errorf("issue is nil: %v", p)
+					}
+
+					mu.Lock()
+					switch *p.Action {
+					case "opened":
+						addIssue(report.OpenedIssues, repoKey, *issue.Number, githubIssue(issue))
+					case "closed":
+						addIssue(report.ClosedIssues, repoKey, *issue.Number, githubIssue(issue))
+					}
+					mu.Unlock()
+				case *github.PullRequestEvent:
+					pullRequest := p.PullRequest
+					if pullRequest == nil {
+						return fmt.Errorf("pullRequest is nil: %v", p)
+					}
+
+					mu.Lock()
+					switch *p.Action {
+					case "created", "opened", "reopened":
+						addPullRequest(report.OpenedPullRequests, repoKey, *pullRequest.Number, githubPullRequest(pullRequest))
+					case "closed": // Heh.
+						addPullRequest(report.ClosedPullRequests, repoKey, *pullRequest.Number, githubPullRequest(pullRequest))
+					}
+					mu.Unlock()
+				case *github.PullRequestReviewCommentEvent:
+					pullRequest := p.PullRequest
+					if pullRequest == nil {
+						return fmt.Errorf("pullRequest is nil: %v", p)
+					}
+
+					if *p.Action == "created" {
+						mu.Lock()
+						addPullRequest(report.ReviewedPullRequests, repoKey, *pullRequest.Number, githubPullRequest(pullRequest))
+						mu.Unlock()
+					}
+				default:
+					// Ignore.
+					continue
+				}
+			}
+
+			// Pages will loop around, if the next page is less, we have already seen it.
+			if options.Page == resp.LastPage || resp.NextPage < options.Page {
+				return nil
+			}
+			options.Page = resp.NextPage
+		}
+	}()
+
+	close(lookups)
+	workers.Wait()
+
+	if paginationErr != nil {
+		return nil, paginationErr
+	}
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+
+	return report, nil
+}
+
+// fetchPullRequest looks up the pull request described by lookup,
+// retrying with a delay if GitHub responds with a secondary rate limit
+// (HTTP 403 abuse detection, up to maxAbuseRetries times) or a primary
+// rate limit (HTTP 403/429 with Rate.Remaining exhausted). Any other
+// forbidden response is treated as terminal rather than retried, since
+// it's not something waiting will fix.
+func (s *GitHubSource) fetchPullRequest(ctx context.Context, lookup pullRequestLookup) (*github.PullRequest, error) {
+	abuseRetries := 0
+	for {
+		pull, resp, err := s.Client.PullRequests.Get(ctx, lookup.owner, lookup.repo, lookup.number)
+		if err == nil {
+			fmt.Fprintf(stderr, "Fetched %s/%s#%d (rate limit remaining: %d/%d)\n",
+				lookup.owner, lookup.repo, lookup.number, resp.Rate.Remaining, resp.Rate.Limit)
+			if resp.Rate.Remaining < minRateLimitRemaining {
+				if err := waitForRateLimitReset(ctx, resp.Rate); err != nil {
+					return nil, err
+				}
+			}
+			return pull, nil
+		}
+
+		var abuseErr *github.AbuseRateLimitError
+		if errors.As(err, &abuseErr) {
+			if abuseRetries >= maxAbuseRetries {
+				return nil, fmt.Errorf("giving up on %s/%s#%d after %d secondary rate limit retries: %w",
+					lookup.owner, lookup.repo, lookup.number, abuseRetries, err)
+			}
+			abuseRetries++
+			retryAfter := time.Second
+			if abuseErr.RetryAfter != nil {
+				retryAfter = *abuseErr.RetryAfter
+			}
+			fmt.Fprintf(stderr, "Secondary rate limit hit for %s/%s#%d, backing off %s\n",
+				lookup.owner, lookup.repo, lookup.number, retryAfter)
+			select {
+			case <-time.After(retryAfter):
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		var rateLimitErr *github.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			fmt.Fprintf(stderr, "Primary rate limit hit for %s/%s#%d, waiting until %s\n",
+				lookup.owner, lookup.repo, lookup.number, rateLimitErr.Rate.Reset.Time)
+			if err := waitForRateLimitReset(ctx, rateLimitErr.Rate); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return nil, err
+	}
+}
+
+// waitForRateLimitReset sleeps until rate.Reset, the time GitHub reports
+// the primary rate limit will next refill.
+func waitForRateLimitReset(ctx context.Context, rate github.Rate) error {
+	wait := time.Until(rate.Reset.Time)
+	if wait <= 0 {
+		return nil
+	}
+	fmt.Fprintf(stderr, "Rate limit remaining below %d, pausing %s until it resets\n", minRateLimitRemaining, wait)
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// splitOwnerRepo splits a "owner/repo" full name into its two parts.
+func splitOwnerRepo(fullName string) (owner, repo string) {
+	s := strings.SplitN(fullName, "/", 2)
+	return s[0], s[1]
+}
+
+func githubIssue(i *github.Issue) *Issue {
+	issue := &Issue{Number: *i.Number, Title: *i.Title, URL: *i.HTMLURL, Labels: githubLabels(i.Labels)}
+	if i.Milestone != nil {
+		issue.Milestone = i.Milestone.GetTitle()
+	}
+	return issue
+}
+
+func githubPullRequest(p *github.PullRequest) *PullRequest {
+	pr := &PullRequest{Number: *p.Number, Title: *p.Title, URL: *p.HTMLURL, Merged: p.Merged, Labels: githubLabels(p.Labels)}
+	if p.Milestone != nil {
+		pr.Milestone = p.Milestone.GetTitle()
+	}
+	return pr
+}
+
+func githubLabels(labels []*github.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.GetName())
+	}
+	return names
+}
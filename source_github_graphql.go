@@ -0,0 +1,364 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// githubGraphQLEndpoint is GitHub's v4 API endpoint.
+const githubGraphQLEndpoint = "https://api.github.com/graphql"
+
+// GitHubGraphQLSource fetches activity from github.com using the v4
+// GraphQL API's contributionsCollection, rather than the REST events
+// endpoint used by GitHubSource. Unlike the REST events API, which only
+// returns roughly the last 90 days and up to 300 events,
+// contributionsCollection accepts an arbitrary from/to range and is not
+// subject to an event-count ceiling.
+type GitHubGraphQLSource struct {
+	Client *http.Client
+	Token  string
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// graphQLLabels is the shape GitHub returns for a "labels(first: N) {
+// nodes { name } }" selection, shared by Issue and PullRequest nodes.
+type graphQLLabels struct {
+	Nodes []struct {
+		Name string `json:"name"`
+	} `json:"nodes"`
+}
+
+func (l graphQLLabels) names() []string {
+	if len(l.Nodes) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(l.Nodes))
+	for _, n := range l.Nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+type contributionNode struct {
+	Issue *struct {
+		Number    int           `json:"number"`
+		Title     string        `json:"title"`
+		URL       string        `json:"url"`
+		Labels    graphQLLabels `json:"labels"`
+		Milestone *struct {
+			Title string `json:"title"`
+		} `json:"milestone"`
+		Repository struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"repository"`
+	} `json:"issue"`
+	PullRequest *struct {
+		Number    int           `json:"number"`
+		Title     string        `json:"title"`
+		URL       string        `json:"url"`
+		Merged    bool          `json:"merged"`
+		Closed    bool          `json:"closed"`
+		Labels    graphQLLabels `json:"labels"`
+		Milestone *struct {
+			Title string `json:"title"`
+		} `json:"milestone"`
+		Repository struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"repository"`
+	} `json:"pullRequest"`
+}
+
+type contributionConnection struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []contributionNode `json:"nodes"`
+}
+
+type commitContributionsByRepository struct {
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+	Contributions struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"contributions"`
+}
+
+type contributionsCollection struct {
+	IssueContributions              contributionConnection            `json:"issueContributions"`
+	PullRequestContributions        contributionConnection            `json:"pullRequestContributions"`
+	PullRequestReviewContributions  contributionConnection            `json:"pullRequestReviewContributions"`
+	CommitContributionsByRepository []commitContributionsByRepository `json:"commitContributionsByRepository"`
+}
+
+type graphQLResponse struct {
+	Data struct {
+		User struct {
+			ContributionsCollection contributionsCollection `json:"contributionsCollection"`
+		} `json:"user"`
+	} `json:"data"`
+	Errors []graphQLError `json:"errors"`
+}
+
+// FetchActivity implements Source.
+func (s *GitHubGraphQLSource) FetchActivity(ctx context.Context, user string, start, end time.Time) (*Report, error) {
+	report := newReport()
+
+	issues, err := s.paginate(ctx, user, start, end, "issueContributions")
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range issues {
+		if n.Issue == nil {
+			continue
+		}
+		// issueContributions is GitHub's "issues I opened" connection; it
+		// carries no "issues I closed" signal, so every node here is
+		// opened-activity regardless of the issue's current state.
+		repoKey := "github.com/" + n.Issue.Repository.NameWithOwner
+		issue := &Issue{Number: n.Issue.Number, Title: n.Issue.Title, URL: n.Issue.URL, Labels: n.Issue.Labels.names()}
+		if n.Issue.Milestone != nil {
+			issue.Milestone = n.Issue.Milestone.Title
+		}
+		addIssue(report.OpenedIssues, repoKey, n.Issue.Number, issue)
+	}
+
+	pulls, err := s.paginate(ctx, user, start, end, "pullRequestContributions")
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range pulls {
+		if n.PullRequest == nil {
+			continue
+		}
+		merged := n.PullRequest.Merged
+		repoKey := "github.com/" + n.PullRequest.Repository.NameWithOwner
+		pr := &PullRequest{
+			Number: n.PullRequest.Number, Title: n.PullRequest.Title, URL: n.PullRequest.URL,
+			Merged: &merged, Labels: n.PullRequest.Labels.names(),
+		}
+		if n.PullRequest.Milestone != nil {
+			pr.Milestone = n.PullRequest.Milestone.Title
+		}
+		if n.PullRequest.Merged || n.PullRequest.Closed {
+			addPullRequest(report.ClosedPullRequests, repoKey, n.PullRequest.Number, pr)
+		} else {
+			addPullRequest(report.OpenedPullRequests, repoKey, n.PullRequest.Number, pr)
+		}
+	}
+
+	reviews, err := s.paginate(ctx, user, start, end, "pullRequestReviewContributions")
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range reviews {
+		if n.PullRequest == nil {
+			continue
+		}
+		merged := n.PullRequest.Merged
+		repoKey := "github.com/" + n.PullRequest.Repository.NameWithOwner
+		reviewed := &PullRequest{
+			Number: n.PullRequest.Number, Title: n.PullRequest.Title, URL: n.PullRequest.URL,
+			Merged: &merged, Labels: n.PullRequest.Labels.names(),
+		}
+		if n.PullRequest.Milestone != nil {
+			reviewed.Milestone = n.PullRequest.Milestone.Title
+		}
+		addPullRequest(report.ReviewedPullRequests, repoKey, n.PullRequest.Number, reviewed)
+	}
+
+	commits, err := s.queryCommits(ctx, user, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range commits {
+		repoKey := "github.com/" + c.Repository.NameWithOwner
+		report.Commits[repoKey] += c.Contributions.TotalCount
+	}
+
+	return report, nil
+}
+
+// paginate walks every page of the named contributionsCollection
+// connection (issueContributions, pullRequestContributions, or
+// pullRequestReviewContributions), fetching first:100 nodes at a time.
+// Each page queries only the connection being walked, rather than all
+// three at once, to avoid re-fetching unrelated connections' first pages
+// on every round trip.
+func (s *GitHubGraphQLSource) paginate(ctx context.Context, user string, start, end time.Time, connection string) ([]contributionNode, error) {
+	query, ok := connectionQueries[connection]
+	if !ok {
+		return nil, fmt.Errorf("lastweek: unknown contributionsCollection connection %q", connection)
+	}
+
+	var (
+		nodes  []contributionNode
+		cursor string
+	)
+	for {
+		variables := map[string]interface{}{
+			"login": user,
+			"from":  start.Format(time.RFC3339),
+			"to":    end.Format(time.RFC3339),
+		}
+		if cursor != "" {
+			variables["cursor"] = cursor
+		}
+
+		collection, err := s.query(ctx, query, variables)
+		if err != nil {
+			return nil, err
+		}
+
+		var conn contributionConnection
+		switch connection {
+		case "issueContributions":
+			conn = collection.IssueContributions
+		case "pullRequestContributions":
+			conn = collection.PullRequestContributions
+		case "pullRequestReviewContributions":
+			conn = collection.PullRequestReviewContributions
+		}
+
+		nodes = append(nodes, conn.Nodes...)
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		cursor = conn.PageInfo.EndCursor
+	}
+	return nodes, nil
+}
+
+// queryCommits fetches commitContributionsByRepository, which GitHub does
+// not paginate: it returns every repository the user pushed to in range
+// in a single page.
+func (s *GitHubGraphQLSource) queryCommits(ctx context.Context, user string, start, end time.Time) ([]commitContributionsByRepository, error) {
+	collection, err := s.query(ctx, commitContributionsQuery, map[string]interface{}{
+		"login": user,
+		"from":  start.Format(time.RFC3339),
+		"to":    end.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collection.CommitContributionsByRepository, nil
+}
+
+// connectionQueries maps a contributionsCollection connection name to the
+// query that fetches only that connection, one page at a time.
+var connectionQueries = map[string]string{
+	"issueContributions":             issueContributionsQuery,
+	"pullRequestContributions":       pullRequestContributionsQuery,
+	"pullRequestReviewContributions": pullRequestReviewContributionsQuery,
+}
+
+const issueContributionsQuery = `
+query($login: String!, $from: DateTime!, $to: DateTime!, $cursor: String) {
+  user(login: $login) {
+    contributionsCollection(from: $from, to: $to) {
+      issueContributions(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes { issue { number title url labels(first: 20) { nodes { name } } milestone { title } repository { nameWithOwner } } }
+      }
+    }
+  }
+}`
+
+const pullRequestContributionsQuery = `
+query($login: String!, $from: DateTime!, $to: DateTime!, $cursor: String) {
+  user(login: $login) {
+    contributionsCollection(from: $from, to: $to) {
+      pullRequestContributions(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes { pullRequest { number title url merged closed labels(first: 20) { nodes { name } } milestone { title } repository { nameWithOwner } } }
+      }
+    }
+  }
+}`
+
+const pullRequestReviewContributionsQuery = `
+query($login: String!, $from: DateTime!, $to: DateTime!, $cursor: String) {
+  user(login: $login) {
+    contributionsCollection(from: $from, to: $to) {
+      pullRequestReviewContributions(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes { pullRequest { number title url merged closed labels(first: 20) { nodes { name } } milestone { title } repository { nameWithOwner } } }
+      }
+    }
+  }
+}`
+
+const commitContributionsQuery = `
+query($login: String!, $from: DateTime!, $to: DateTime!) {
+  user(login: $login) {
+    contributionsCollection(from: $from, to: $to) {
+      commitContributionsByRepository {
+        repository { nameWithOwner }
+        contributions { totalCount }
+      }
+    }
+  }
+}`
+
+func (s *GitHubGraphQLSource) query(ctx context.Context, query string, variables map[string]interface{}) (*contributionsCollection, error) {
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubGraphQLEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "bearer "+s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse graphql response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("github graphql error: %s", result.Errors[0].Message)
+	}
+
+	return &result.Data.User.ContributionsCollection, nil
+}
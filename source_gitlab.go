@@ -0,0 +1,203 @@
+// Copyright 2022 lastweek authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// gitlabEvent is the subset of GitLab's Event entity that lastweek cares
+// about.
+type gitlabEvent struct {
+	ActionName  string `json:"action_name"`
+	TargetType  string `json:"target_type"`
+	TargetIID   int    `json:"target_iid"`
+	TargetTitle string `json:"target_title"`
+	ProjectID   int    `json:"project_id"`
+}
+
+type gitlabUser struct {
+	ID int `json:"id"`
+}
+
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+}
+
+// GitLabSource fetches activity from a GitLab instance (gitlab.com or a
+// self-hosted installation) using the user events API.
+type GitLabSource struct {
+	// BaseURL is the root of the GitLab instance, e.g. "https://gitlab.com".
+	BaseURL string
+	Token   string
+	Client  *http.Client
+
+	// Username, if set, is looked up on this instance instead of the
+	// user passed to FetchActivity. lastweek only takes one --user
+	// value (your GitHub login); set this when your GitLab username
+	// differs from it.
+	Username string
+}
+
+// FetchActivity implements Source.
+func (s *GitLabSource) FetchActivity(ctx context.Context, user string, start, end time.Time) (*Report, error) {
+	report := newReport()
+
+	if s.Username != "" {
+		user = s.Username
+	}
+
+	userID, err := s.lookupUserID(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	host := hostFromURL(s.BaseURL)
+	projects := make(map[int]*gitlabProject)
+
+	// GitLab's "after" is exclusive of the given date, unlike "before",
+	// which is already an exclusive upper bound; back it up a day so
+	// start-day activity isn't silently dropped.
+	after := start.AddDate(0, 0, -1)
+
+	page := "1"
+	for page != "" {
+		reqURL := fmt.Sprintf("%s/api/v4/users/%d/events?after=%s&before=%s&per_page=100&page=%s",
+			strings.TrimRight(s.BaseURL, "/"), userID,
+			after.Format("2006-01-02"), end.Format("2006-01-02"), page)
+
+		var events []gitlabEvent
+		nextPage, err := s.getPage(ctx, reqURL, &events)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			project, ok := projects[e.ProjectID]
+			if !ok {
+				project, err = s.lookupProject(ctx, e.ProjectID)
+				if err != nil {
+					return nil, err
+				}
+				projects[e.ProjectID] = project
+			}
+			repoKey := host + "/" + project.PathWithNamespace
+
+			switch e.TargetType {
+			case "Issue":
+				issue := &Issue{
+					Number: e.TargetIID,
+					Title:  e.TargetTitle,
+					URL:    fmt.Sprintf("%s/-/issues/%d", project.WebURL, e.TargetIID),
+				}
+				switch e.ActionName {
+				case "opened":
+					addIssue(report.OpenedIssues, repoKey, issue.Number, issue)
+				case "closed":
+					addIssue(report.ClosedIssues, repoKey, issue.Number, issue)
+				case "commented on":
+					addIssue(report.CommentedIssues, repoKey, issue.Number, issue)
+				}
+			case "MergeRequest":
+				mr := &PullRequest{
+					Number: e.TargetIID,
+					Title:  e.TargetTitle,
+					URL:    fmt.Sprintf("%s/-/merge_requests/%d", project.WebURL, e.TargetIID),
+				}
+				switch e.ActionName {
+				case "opened":
+					addPullRequest(report.OpenedPullRequests, repoKey, mr.Number, mr)
+				case "closed", "merged":
+					merged := e.ActionName == "merged"
+					mr.Merged = &merged
+					addPullRequest(report.ClosedPullRequests, repoKey, mr.Number, mr)
+				case "commented on":
+					addPullRequest(report.ReviewedPullRequests, repoKey, mr.Number, mr)
+				}
+			}
+		}
+
+		page = nextPage
+	}
+
+	return report, nil
+}
+
+func (s *GitLabSource) lookupUserID(ctx context.Context, username string) (int, error) {
+	var users []gitlabUser
+	reqURL := fmt.Sprintf("%s/api/v4/users?username=%s",
+		strings.TrimRight(s.BaseURL, "/"), url.QueryEscape(username))
+	if err := s.get(ctx, reqURL, &users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("gitlab: no user found for username %q", username)
+	}
+	return users[0].ID, nil
+}
+
+func (s *GitLabSource) lookupProject(ctx context.Context, id int) (*gitlabProject, error) {
+	var project gitlabProject
+	reqURL := fmt.Sprintf("%s/api/v4/projects/%d", strings.TrimRight(s.BaseURL, "/"), id)
+	if err := s.get(ctx, reqURL, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *GitLabSource) get(ctx context.Context, reqURL string, v interface{}) error {
+	_, err := s.getPage(ctx, reqURL, v)
+	return err
+}
+
+// getPage issues a GET request and decodes the response body into v,
+// returning the value of the X-Next-Page response header (GitLab's
+// offset-pagination cue for list endpoints), which is empty once the
+// last page has been reached.
+func (s *GitLabSource) getPage(ctx context.Context, reqURL string, v interface{}) (nextPage string, err error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if s.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", s.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gitlab: unexpected status %s for %s", resp.Status, reqURL)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("X-Next-Page"), nil
+}